@@ -0,0 +1,100 @@
+// Package apierror renders API failures as RFC 7807 problem+json bodies
+// and maps internal sentinel errors to the predefined problem they should
+// surface as.
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Timok27/testtask/sample-app/ledger"
+	"github.com/Timok27/testtask/sample-app/repository"
+)
+
+// APIError is a problem+json document: a machine-readable Code, a short
+// Title, an optional human-readable Detail, and the HTTP Status it maps to.
+// Errors carries per-field validation failures, when there are any.
+type APIError struct {
+	Code   string       `json:"code"`
+	Title  string       `json:"title"`
+	Detail string       `json:"detail,omitempty"`
+	Status int          `json:"status"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes a single invalid field in a request body.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	if e.Detail != "" {
+		return e.Detail
+	}
+	return e.Title
+}
+
+// Predefined errors handlers can return directly or match against with
+// errors.Is.
+var (
+	ErrAccountNotFound         = &APIError{Code: "account_not_found", Title: "Account not found", Status: http.StatusNotFound}
+	ErrInsufficientFunds       = &APIError{Code: "insufficient_funds", Title: "Insufficient funds", Status: http.StatusBadRequest}
+	ErrInvalidAmount           = &APIError{Code: "invalid_amount", Title: "Amount must be a positive number", Status: http.StatusBadRequest}
+	ErrDuplicateIdempotencyKey = &APIError{Code: "duplicate_idempotency_key", Title: "Idempotency key already used with a different request", Status: http.StatusConflict}
+	ErrInvalidRequest          = &APIError{Code: "invalid_request", Title: "Request body could not be parsed", Status: http.StatusBadRequest}
+	ErrInvalidCursor           = &APIError{Code: "invalid_cursor", Title: "cursor query parameter is not valid", Status: http.StatusBadRequest}
+	ErrUnauthorized            = &APIError{Code: "unauthorized", Title: "Missing or invalid bearer token", Status: http.StatusUnauthorized}
+	ErrForbidden               = &APIError{Code: "forbidden", Title: "You do not have access to this account", Status: http.StatusForbidden}
+	ErrInternal                = &APIError{Code: "internal_error", Title: "Internal server error", Status: http.StatusInternalServerError}
+)
+
+// ValidationError builds the APIError returned when a request body fails
+// field-level validation.
+func ValidationError(fieldErrs []FieldError) *APIError {
+	return &APIError{
+		Code:   "validation_failed",
+		Title:  "Request failed validation",
+		Status: http.StatusBadRequest,
+		Errors: fieldErrs,
+	}
+}
+
+// WriteError renders err as application/problem+json. If err isn't already
+// an *APIError, it's mapped via errors.Is against the ledger's sentinel
+// errors, falling back to ErrInternal.
+func WriteError(w http.ResponseWriter, err error) {
+	Write(w, Map(err))
+}
+
+// Write renders apiErr as application/problem+json.
+func Write(w http.ResponseWriter, apiErr *APIError) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(apiErr.Status)
+	json.NewEncoder(w).Encode(apiErr)
+}
+
+// Map resolves err to the APIError it should surface as.
+func Map(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	switch {
+	case errors.Is(err, ledger.ErrAccountNotFound):
+		return ErrAccountNotFound
+	case errors.Is(err, ledger.ErrInsufficientFunds):
+		return ErrInsufficientFunds
+	case errors.Is(err, ledger.ErrInvalidCursor):
+		return ErrInvalidCursor
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrAccountNotFound
+	case errors.Is(err, repository.ErrForbidden):
+		return ErrForbidden
+	default:
+		log.Printf("unmapped error: %v", err)
+		return ErrInternal
+	}
+}
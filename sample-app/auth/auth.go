@@ -0,0 +1,72 @@
+// Package auth authenticates inbound requests and injects the resulting
+// Principal into the request context.
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/Timok27/testtask/sample-app/apierror"
+)
+
+// Principal identifies the caller a request was authenticated as.
+type Principal struct {
+	UserID string
+	Scopes []string
+}
+
+// Verifier turns a bearer token into the Principal it represents.
+type Verifier interface {
+	Verify(ctx context.Context, token string) (Principal, error)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying p.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the Principal a Middleware injected, if any.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// Middleware validates the Authorization: Bearer <token> header with
+// verifier and injects the resulting Principal into the request context.
+// Requests that fail verification get a 401 problem+json response.
+func Middleware(verifier Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				apierror.Write(w, apierror.ErrUnauthorized)
+				return
+			}
+
+			principal, err := verifier.Verify(r.Context(), token)
+			if err != nil {
+				apierror.Write(w, apierror.ErrUnauthorized)
+				return
+			}
+
+			r = r.WithContext(WithPrincipal(r.Context(), principal))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "Bearer "
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, prefix) {
+		return "", false
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(header, prefix))
+	if token == "" {
+		return "", false
+	}
+	return token, true
+}
@@ -0,0 +1,50 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Timok27/testtask/sample-app/auth"
+)
+
+func TestMiddlewareInjectsPrincipal(t *testing.T) {
+	var gotUserID string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := auth.FromContext(r.Context())
+		if !ok {
+			t.Fatal("expected principal in context")
+		}
+		gotUserID = p.UserID
+		w.WriteHeader(http.StatusOK)
+	})
+
+	handler := auth.Middleware(auth.StaticTokenVerifier{})(next)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer user-123")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if gotUserID != "user-123" {
+		t.Fatalf("UserID = %q, want %q", gotUserID, "user-123")
+	}
+}
+
+func TestMiddlewareRejectsMissingToken(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler should not run without a token")
+	})
+	handler := auth.Middleware(auth.StaticTokenVerifier{})(next)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
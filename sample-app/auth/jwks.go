@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWKSVerifier verifies RS256 bearer tokens against a JWKS endpoint,
+// refreshing the key set once it's older than refreshInterval.
+type JWKSVerifier struct {
+	jwksURL         string
+	refreshInterval time.Duration
+	httpClient      *http.Client
+
+	mu        sync.RWMutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// NewJWKSVerifier builds a JWKSVerifier that fetches keys from jwksURL.
+func NewJWKSVerifier(jwksURL string) *JWKSVerifier {
+	return &JWKSVerifier{
+		jwksURL:         jwksURL,
+		refreshInterval: 10 * time.Minute,
+		httpClient:      &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Verify implements Verifier. The token's "sub" claim becomes the
+// Principal's UserID and its "scope" claim (space-separated) the Scopes.
+func (v *JWKSVerifier) Verify(ctx context.Context, tokenString string) (Principal, error) {
+	token, err := jwt.Parse(tokenString, v.keyFor, jwt.WithValidMethods([]string{"RS256"}))
+	if err != nil {
+		return Principal{}, fmt.Errorf("verify jwt: %w", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return Principal{}, errors.New("auth: invalid token claims")
+	}
+
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return Principal{}, errors.New("auth: token missing sub claim")
+	}
+
+	var scopes []string
+	if scope, _ := claims["scope"].(string); scope != "" {
+		scopes = append(scopes, splitScope(scope)...)
+	}
+
+	return Principal{UserID: sub, Scopes: scopes}, nil
+}
+
+func (v *JWKSVerifier) keyFor(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("auth: token missing kid header")
+	}
+
+	key, err := v.lookupKey(context.Background(), kid)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (v *JWKSVerifier) lookupKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	v.mu.RLock()
+	key, ok := v.keys[kid]
+	stale := time.Since(v.fetchedAt) > v.refreshInterval
+	v.mu.RUnlock()
+	if ok && !stale {
+		return key, nil
+	}
+
+	if err := v.refresh(ctx); err != nil {
+		if ok {
+			return key, nil // serve the stale key rather than fail a valid token
+		}
+		return nil, err
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	key, ok = v.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("auth: unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+func (v *JWKSVerifier) refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.jwksURL, nil)
+	if err != nil {
+		return fmt.Errorf("build jwks request: %w", err)
+	}
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var set jwks
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("decode jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		pub, err := rsaPublicKey(k.N, k.E)
+		if err != nil {
+			return fmt.Errorf("parse key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = pub
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.fetchedAt = time.Now()
+	v.mu.Unlock()
+	return nil
+}
+
+func rsaPublicKey(nEncoded, eEncoded string) (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(nEncoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode modulus: %w", err)
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(eEncoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode exponent: %w", err)
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(nBytes),
+		E: int(new(big.Int).SetBytes(eBytes).Int64()),
+	}, nil
+}
+
+func splitScope(scope string) []string {
+	var scopes []string
+	start := 0
+	for i, r := range scope {
+		if r == ' ' {
+			if i > start {
+				scopes = append(scopes, scope[start:i])
+			}
+			start = i + 1
+		}
+	}
+	if start < len(scope) {
+		scopes = append(scopes, scope[start:])
+	}
+	return scopes
+}
@@ -0,0 +1,68 @@
+// Package dto defines the JSON request bodies accepted by the HTTP API and
+// validates them before the account layer ever sees them.
+package dto
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/Timok27/testtask/sample-app/apierror"
+)
+
+var validate = validator.New()
+
+// DepositRequest is the body of POST /accounts/{id}/deposit.
+type DepositRequest struct {
+	Amount float64 `json:"amount" validate:"required,gt=0"`
+}
+
+// WithdrawRequest is the body of POST /accounts/{id}/withdraw.
+type WithdrawRequest struct {
+	Amount float64 `json:"amount" validate:"required,gt=0"`
+}
+
+// TransferRequest is the body of POST /accounts/{id}/transfer. IdempotencyKey
+// is optional here because it can instead be supplied via the
+// Idempotency-Key header; the handler enforces that at least one is set.
+type TransferRequest struct {
+	ToAccountID    int     `json:"to_account_id" validate:"required,gt=0"`
+	Amount         float64 `json:"amount" validate:"required,gt=0"`
+	IdempotencyKey string  `json:"idempotency_key" validate:"omitempty"`
+}
+
+// CreateAccountRequest is the body of POST /accounts. Both fields are
+// optional: an account can be created empty and funded later.
+type CreateAccountRequest struct {
+	InitialDeposit float64 `json:"initial_deposit" validate:"omitempty,gte=0"`
+	Currency       string  `json:"currency" validate:"omitempty,len=3"`
+}
+
+// BindAndValidate decodes r's JSON body into dst and validates it. An empty
+// body is treated as a zero-valued request so optional fields still pass.
+// It returns nil on success, or the *apierror.APIError to send back.
+func BindAndValidate(r *http.Request, dst any) *apierror.APIError {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil && !errors.Is(err, io.EOF) {
+		return apierror.ErrInvalidRequest
+	}
+
+	if err := validate.Struct(dst); err != nil {
+		var verrs validator.ValidationErrors
+		if !errors.As(err, &verrs) {
+			return apierror.ErrInvalidRequest
+		}
+		fieldErrs := make([]apierror.FieldError, 0, len(verrs))
+		for _, fe := range verrs {
+			fieldErrs = append(fieldErrs, apierror.FieldError{
+				Field:   fe.Field(),
+				Message: fmt.Sprintf("failed on the '%s' tag", fe.Tag()),
+			})
+		}
+		return apierror.ValidationError(fieldErrs)
+	}
+	return nil
+}
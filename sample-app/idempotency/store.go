@@ -0,0 +1,79 @@
+// Package idempotency caches handler responses by idempotency key so a
+// retried request gets back the original result instead of being applied
+// twice.
+package idempotency
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrConflict is returned by Execute when key was already used for a
+// request with a different fingerprint.
+var ErrConflict = errors.New("idempotency: key reused for a different request")
+
+// Response is the cached outcome of handling a request for a given key.
+type Response struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// entry pairs a cached Response with a fingerprint of the request that
+// produced it, so a key reused with different request parameters can be
+// rejected instead of silently replayed.
+type entry struct {
+	fingerprint string
+	resp        Response
+}
+
+// Store is a TTL-bounded, size-bounded cache of idempotency keys to the
+// response they produced. Execute uses a singleflight.Group to serialize
+// concurrent callers sharing a key, so the underlying work runs at most
+// once per key even while the first caller's request is still in flight.
+type Store struct {
+	cache *expirable.LRU[string, entry]
+	group singleflight.Group
+}
+
+// New builds a Store holding at most size entries, each expiring after ttl.
+func New(size int, ttl time.Duration) *Store {
+	return &Store{cache: expirable.NewLRU[string, entry](size, nil, ttl)}
+}
+
+// Execute scopes key to owner and returns the cached Response if one
+// already exists for that (owner, key) pair and was produced by a request
+// with the same fingerprint. Otherwise it runs fn, caching and returning
+// its result. Concurrent calls sharing an (owner, key) pair block on the
+// same singleflight call instead of all running fn, so a retry racing the
+// original request can never apply twice; a panic inside fn is recovered
+// by the singleflight.Group and propagated to callers instead of leaving
+// them blocked forever. If (owner, key) was already used with a different
+// fingerprint, Execute returns ErrConflict without running fn.
+func (s *Store) Execute(owner, key, fingerprint string, fn func() Response) (Response, error) {
+	// Length-prefix owner so two (owner, key) pairs can never concatenate
+	// to the same scoped key, even if owner or key contain ':'.
+	scopedKey := strconv.Itoa(len(owner)) + ":" + owner + key
+
+	v, err, _ := s.group.Do(scopedKey, func() (any, error) {
+		if e, ok := s.cache.Get(scopedKey); ok {
+			return e, nil
+		}
+		e := entry{fingerprint: fingerprint, resp: fn()}
+		s.cache.Add(scopedKey, e)
+		return e, nil
+	})
+	if err != nil {
+		return Response{}, err
+	}
+
+	e := v.(entry)
+	if e.fingerprint != fingerprint {
+		return Response{}, ErrConflict
+	}
+	return e.resp, nil
+}
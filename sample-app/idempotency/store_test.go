@@ -0,0 +1,108 @@
+package idempotency_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Timok27/testtask/sample-app/idempotency"
+)
+
+func TestExecuteRunsOncePerKeyUnderConcurrency(t *testing.T) {
+	s := idempotency.New(10, time.Minute)
+
+	var calls int32
+	const concurrency = 20
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.Execute("owner", "key", "fp", func() idempotency.Response {
+				atomic.AddInt32(&calls, 1)
+				time.Sleep(5 * time.Millisecond)
+				return idempotency.Response{StatusCode: 200}
+			})
+			if err != nil {
+				t.Errorf("Execute: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("fn called %d times, want 1", got)
+	}
+}
+
+func TestExecuteRejectsConflictingFingerprint(t *testing.T) {
+	s := idempotency.New(10, time.Minute)
+
+	if _, err := s.Execute("owner", "key", "fp-a", func() idempotency.Response {
+		return idempotency.Response{StatusCode: 200}
+	}); err != nil {
+		t.Fatalf("first Execute: %v", err)
+	}
+
+	_, err := s.Execute("owner", "key", "fp-b", func() idempotency.Response {
+		t.Fatal("fn should not run for a conflicting fingerprint")
+		return idempotency.Response{}
+	})
+	if err != idempotency.ErrConflict {
+		t.Fatalf("err = %v, want ErrConflict", err)
+	}
+}
+
+func TestExecuteScopesKeyByOwner(t *testing.T) {
+	s := idempotency.New(10, time.Minute)
+
+	var calls int32
+	run := func(owner string) {
+		if _, err := s.Execute(owner, "shared-key", "fp", func() idempotency.Response {
+			atomic.AddInt32(&calls, 1)
+			return idempotency.Response{StatusCode: 200}
+		}); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+	}
+
+	run("owner-a")
+	run("owner-b")
+
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("fn called %d times, want 2 (one per owner)", got)
+	}
+}
+
+func TestExecuteRecoversFromPanic(t *testing.T) {
+	s := idempotency.New(10, time.Minute)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected Execute to propagate the panic")
+			}
+		}()
+		s.Execute("owner", "key", "fp", func() idempotency.Response {
+			panic("boom")
+		})
+	}()
+
+	// A later call with the same key must not be stuck behind the earlier,
+	// panicked one.
+	done := make(chan struct{})
+	go func() {
+		s.Execute("owner", "key", "fp", func() idempotency.Response {
+			return idempotency.Response{StatusCode: 200}
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Execute stayed blocked after a previous call panicked")
+	}
+}
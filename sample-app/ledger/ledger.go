@@ -0,0 +1,243 @@
+// Package ledger implements a persistent, double-entry ledger backed by
+// PostgreSQL. Every operation runs inside a single SQL transaction: it
+// locks the account's balance row with SELECT ... FOR UPDATE, applies the
+// change, and appends an immutable entries row describing it.
+package ledger
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Sentinel errors returned by ledger operations.
+var (
+	ErrAccountNotFound   = errors.New("ledger: account not found")
+	ErrInsufficientFunds = errors.New("ledger: insufficient funds")
+	ErrInvalidCursor     = errors.New("ledger: invalid cursor")
+)
+
+const (
+	directionCredit = "credit"
+	directionDebit  = "debit"
+
+	// EntryTypeDeposit through EntryTypeTransferOut are the entry_type
+	// values surfaced in transaction history.
+	EntryTypeDeposit     = "deposit"
+	EntryTypeWithdraw    = "withdraw"
+	EntryTypeTransferIn  = "transfer_in"
+	EntryTypeTransferOut = "transfer_out"
+)
+
+// Entry is one immutable row of an account's transaction history.
+type Entry struct {
+	ID                    int
+	Type                  string
+	Amount                float64
+	CounterpartyAccountID *int
+	CreatedAt             time.Time
+	BalanceAfter          float64
+}
+
+// Ledger is the set of balance-affecting operations a bank account supports.
+type Ledger interface {
+	Deposit(ctx context.Context, accountID int, amount float64) error
+	Withdraw(ctx context.Context, accountID int, amount float64) error
+	Transfer(ctx context.Context, fromID, toID int, amount float64) error
+	Balance(ctx context.Context, accountID int) (float64, error)
+	// Transactions returns accountID's entries newest-first, at most limit
+	// of them, starting after cursor (empty for the first page). nextCursor
+	// is "" once there are no more pages.
+	Transactions(ctx context.Context, accountID, limit int, cursor string) (items []Entry, nextCursor string, err error)
+}
+
+// PostgresLedger is a Ledger backed by a pgx connection pool.
+type PostgresLedger struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresLedger builds a PostgresLedger backed by pool.
+func NewPostgresLedger(pool *pgxpool.Pool) *PostgresLedger {
+	return &PostgresLedger{pool: pool}
+}
+
+// Deposit credits amount to accountID.
+func (l *PostgresLedger) Deposit(ctx context.Context, accountID int, amount float64) error {
+	return l.withTx(ctx, func(tx pgx.Tx) error {
+		if _, err := l.lockBalance(ctx, tx, accountID); err != nil {
+			return err
+		}
+		return l.applyEntry(ctx, tx, accountID, amount, directionCredit, EntryTypeDeposit, nil)
+	})
+}
+
+// Withdraw debits amount from accountID, failing with ErrInsufficientFunds
+// if the account can't cover it.
+func (l *PostgresLedger) Withdraw(ctx context.Context, accountID int, amount float64) error {
+	return l.withTx(ctx, func(tx pgx.Tx) error {
+		balance, err := l.lockBalance(ctx, tx, accountID)
+		if err != nil {
+			return err
+		}
+		if balance < amount {
+			return ErrInsufficientFunds
+		}
+		return l.applyEntry(ctx, tx, accountID, amount, directionDebit, EntryTypeWithdraw, nil)
+	})
+}
+
+// Transfer moves amount from fromID to toID atomically. Balance rows are
+// locked lowest-ID-first so concurrent transfers can't deadlock.
+func (l *PostgresLedger) Transfer(ctx context.Context, fromID, toID int, amount float64) error {
+	return l.withTx(ctx, func(tx pgx.Tx) error {
+		first, second := fromID, toID
+		if second < first {
+			first, second = second, first
+		}
+		balances := map[int]float64{}
+		for _, id := range []int{first, second} {
+			balance, err := l.lockBalance(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+			balances[id] = balance
+		}
+
+		if balances[fromID] < amount {
+			return ErrInsufficientFunds
+		}
+		if err := l.applyEntry(ctx, tx, fromID, amount, directionDebit, EntryTypeTransferOut, &toID); err != nil {
+			return err
+		}
+		return l.applyEntry(ctx, tx, toID, amount, directionCredit, EntryTypeTransferIn, &fromID)
+	})
+}
+
+// Balance reads accountID's current balance as a consistent snapshot.
+func (l *PostgresLedger) Balance(ctx context.Context, accountID int) (float64, error) {
+	var balance float64
+	row := l.pool.QueryRow(ctx, `SELECT amount FROM balances WHERE account_id = $1`, accountID)
+	if err := row.Scan(&balance); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrAccountNotFound
+		}
+		return 0, fmt.Errorf("read balance: %w", err)
+	}
+	return balance, nil
+}
+
+// Transactions returns accountID's entries in reverse chronological order.
+func (l *PostgresLedger) Transactions(ctx context.Context, accountID, limit int, cursor string) ([]Entry, string, error) {
+	afterID, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	rows, err := l.pool.Query(ctx, `
+		SELECT id, entry_type, amount, counterparty_account_id, created_at, balance_after
+		FROM entries
+		WHERE account_id = $1 AND ($2 = 0 OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3`,
+		accountID, afterID, limit,
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("query entries: %w", err)
+	}
+	defer rows.Close()
+
+	var items []Entry
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.ID, &e.Type, &e.Amount, &e.CounterpartyAccountID, &e.CreatedAt, &e.BalanceAfter); err != nil {
+			return nil, "", fmt.Errorf("scan entry: %w", err)
+		}
+		items = append(items, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("read entries: %w", err)
+	}
+
+	nextCursor := ""
+	if len(items) == limit {
+		nextCursor = encodeCursor(items[len(items)-1].ID)
+	}
+	return items, nextCursor, nil
+}
+
+func (l *PostgresLedger) withTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := l.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+func (l *PostgresLedger) lockBalance(ctx context.Context, tx pgx.Tx, accountID int) (float64, error) {
+	var balance float64
+	row := tx.QueryRow(ctx, `SELECT amount FROM balances WHERE account_id = $1 FOR UPDATE`, accountID)
+	if err := row.Scan(&balance); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrAccountNotFound
+		}
+		return 0, fmt.Errorf("lock balance: %w", err)
+	}
+	return balance, nil
+}
+
+func (l *PostgresLedger) applyEntry(ctx context.Context, tx pgx.Tx, accountID int, amount float64, direction, entryType string, counterpartyID *int) error {
+	delta := amount
+	if direction == directionDebit {
+		delta = -amount
+	}
+
+	var balanceAfter float64
+	row := tx.QueryRow(ctx,
+		`UPDATE balances SET amount = amount + $1, updated_at = now() WHERE account_id = $2 RETURNING amount`,
+		delta, accountID,
+	)
+	if err := row.Scan(&balanceAfter); err != nil {
+		return fmt.Errorf("update balance: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO entries (account_id, tx_id, direction, amount, entry_type, counterparty_account_id, balance_after)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		accountID, uuid.New(), direction, amount, entryType, counterpartyID, balanceAfter,
+	); err != nil {
+		return fmt.Errorf("insert entry: %w", err)
+	}
+	return nil
+}
+
+// decodeCursor reverses encodeCursor. An empty cursor means "from the start".
+func decodeCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	decoded, err := base64.URLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(string(decoded))
+}
+
+// encodeCursor opaquely encodes the last entry ID seen on a page.
+func encodeCursor(lastID int) string {
+	return base64.URLEncoding.EncodeToString([]byte(strconv.Itoa(lastID)))
+}
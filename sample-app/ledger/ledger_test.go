@@ -0,0 +1,165 @@
+package ledger_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Timok27/testtask/sample-app/ledger"
+	"github.com/Timok27/testtask/sample-app/repository"
+)
+
+// These tests exercise PostgresLedger against a throwaway database. They
+// need a reachable Postgres instance; point TEST_DATABASE_URL at one (e.g.
+// a disposable docker container) to run them.
+func newTestPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("connect to postgres: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestDepositAndBalance(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPool(t)
+	repo := repository.New(pool)
+	l := ledger.NewPostgresLedger(pool)
+
+	account, err := repo.Create(ctx, "test-owner", "USD")
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+
+	if err := l.Deposit(ctx, account.ID, 100); err != nil {
+		t.Fatalf("deposit: %v", err)
+	}
+
+	balance, err := l.Balance(ctx, account.ID)
+	if err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+	if balance != 100 {
+		t.Fatalf("balance = %v, want 100", balance)
+	}
+}
+
+func TestWithdrawInsufficientFunds(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPool(t)
+	repo := repository.New(pool)
+	l := ledger.NewPostgresLedger(pool)
+
+	account, err := repo.Create(ctx, "test-owner", "USD")
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+
+	if err := l.Withdraw(ctx, account.ID, 1); !errors.Is(err, ledger.ErrInsufficientFunds) {
+		t.Fatalf("withdraw error = %v, want ErrInsufficientFunds", err)
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPool(t)
+	repo := repository.New(pool)
+	l := ledger.NewPostgresLedger(pool)
+
+	from, err := repo.Create(ctx, "test-owner-from", "USD")
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	to, err := repo.Create(ctx, "test-owner-to", "USD")
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+
+	if err := l.Deposit(ctx, from.ID, 50); err != nil {
+		t.Fatalf("deposit: %v", err)
+	}
+	if err := l.Transfer(ctx, from.ID, to.ID, 20); err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+
+	fromBalance, err := l.Balance(ctx, from.ID)
+	if err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+	toBalance, err := l.Balance(ctx, to.ID)
+	if err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+	if fromBalance != 30 || toBalance != 20 {
+		t.Fatalf("fromBalance = %v, toBalance = %v, want 30 and 20", fromBalance, toBalance)
+	}
+}
+
+func TestTransactionsPagination(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPool(t)
+	repo := repository.New(pool)
+	l := ledger.NewPostgresLedger(pool)
+
+	account, err := repo.Create(ctx, "test-owner", "USD")
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := l.Deposit(ctx, account.ID, 10); err != nil {
+			t.Fatalf("deposit: %v", err)
+		}
+	}
+
+	page1, cursor1, err := l.Transactions(ctx, account.ID, 2, "")
+	if err != nil {
+		t.Fatalf("transactions page 1: %v", err)
+	}
+	if len(page1) != 2 || cursor1 == "" {
+		t.Fatalf("page1 = %d items, cursor %q; want 2 items and a non-empty cursor", len(page1), cursor1)
+	}
+
+	page2, cursor2, err := l.Transactions(ctx, account.ID, 2, cursor1)
+	if err != nil {
+		t.Fatalf("transactions page 2: %v", err)
+	}
+	if len(page2) != 2 || cursor2 == "" {
+		t.Fatalf("page2 = %d items, cursor %q; want 2 items and a non-empty cursor", len(page2), cursor2)
+	}
+	if page1[0].ID == page2[0].ID {
+		t.Fatal("page2 returned the same entries as page1")
+	}
+
+	page3, cursor3, err := l.Transactions(ctx, account.ID, 2, cursor2)
+	if err != nil {
+		t.Fatalf("transactions page 3: %v", err)
+	}
+	if len(page3) != 1 || cursor3 != "" {
+		t.Fatalf("page3 = %d items, cursor %q; want 1 item and an empty cursor (last page)", len(page3), cursor3)
+	}
+}
+
+func TestTransactionsInvalidCursor(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPool(t)
+	repo := repository.New(pool)
+	l := ledger.NewPostgresLedger(pool)
+
+	account, err := repo.Create(ctx, "test-owner", "USD")
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+
+	if _, _, err := l.Transactions(ctx, account.ID, 10, "not-valid-base64!!"); !errors.Is(err, ledger.ErrInvalidCursor) {
+		t.Fatalf("transactions error = %v, want ErrInvalidCursor", err)
+	}
+}
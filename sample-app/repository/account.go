@@ -0,0 +1,89 @@
+// Package repository persists account records.
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Sentinel errors returned by the repository.
+var (
+	ErrNotFound  = errors.New("repository: account not found")
+	ErrForbidden = errors.New("repository: account not owned by caller")
+)
+
+// Account is a bank account record. Balances and the entries that produced
+// them live in the ledger tables; Account only carries identity, ownership,
+// and the currency it was opened with.
+type Account struct {
+	ID        int
+	OwnerID   string
+	Currency  string
+	CreatedAt time.Time
+}
+
+// Repository provides CRUD access to accounts.
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// New builds a Repository backed by pool.
+func New(pool *pgxpool.Pool) *Repository {
+	return &Repository{pool: pool}
+}
+
+// Create inserts a new account owned by ownerID in the given currency,
+// along with its zero-balance row.
+func (r *Repository) Create(ctx context.Context, ownerID, currency string) (*Account, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	account := &Account{OwnerID: ownerID, Currency: currency}
+	row := tx.QueryRow(ctx, `INSERT INTO accounts (owner_id, currency) VALUES ($1, $2) RETURNING id, created_at`, ownerID, currency)
+	if err := row.Scan(&account.ID, &account.CreatedAt); err != nil {
+		return nil, fmt.Errorf("insert account: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `INSERT INTO balances (account_id, amount) VALUES ($1, 0)`, account.ID); err != nil {
+		return nil, fmt.Errorf("insert balance: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+	return account, nil
+}
+
+// Get looks up an account by ID.
+func (r *Repository) Get(ctx context.Context, id int) (*Account, error) {
+	account := &Account{}
+	row := r.pool.QueryRow(ctx, `SELECT id, owner_id, currency, created_at FROM accounts WHERE id = $1`, id)
+	if err := row.Scan(&account.ID, &account.OwnerID, &account.Currency, &account.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("get account %d: %w", id, err)
+	}
+	return account, nil
+}
+
+// CheckOwner verifies that ownerID owns accountID, returning ErrForbidden if
+// not (or ErrNotFound if the account doesn't exist at all).
+func (r *Repository) CheckOwner(ctx context.Context, accountID int, ownerID string) error {
+	account, err := r.Get(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	if account.OwnerID != ownerID {
+		return ErrForbidden
+	}
+	return nil
+}
@@ -1,164 +1,357 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
-	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Timok27/testtask/sample-app/apierror"
+	"github.com/Timok27/testtask/sample-app/auth"
+	"github.com/Timok27/testtask/sample-app/dto"
+	"github.com/Timok27/testtask/sample-app/idempotency"
+	"github.com/Timok27/testtask/sample-app/ledger"
+	"github.com/Timok27/testtask/sample-app/middleware"
+	"github.com/Timok27/testtask/sample-app/repository"
 )
 
-type BankAccount interface {
-	Deposit(amount float64) error
-	Withdraw(amount float64) error
-	GetBalance() float64
-}
+const requestTimeout = 5 * time.Second
 
-type Account struct {
-	ID      int
-	Balance float64
-	mutex   sync.Mutex
+// accountAuthorizer checks whether a caller owns a given account. It's
+// satisfied by *repository.Repository; tests can substitute a stub.
+type accountAuthorizer interface {
+	CheckOwner(ctx context.Context, accountID int, ownerID string) error
 }
 
-var accounts = make(map[int]*Account)
-var idCounter = 1
-var mu sync.Mutex
-
-func (a *Account) Deposit(amount float64) error {
-	a.mutex.Lock()
-	defer a.mutex.Unlock()
-	a.Balance += amount
-	return nil
+// server holds the dependencies HTTP handlers need.
+type server struct {
+	repo        *repository.Repository
+	authz       accountAuthorizer
+	ledger      ledger.Ledger
+	idempotency *idempotency.Store
 }
 
-func (a *Account) Withdraw(amount float64) error {
-	a.mutex.Lock()
-	defer a.mutex.Unlock()
-	if a.Balance < amount {
-		return fmt.Errorf("insufficient funds")
+// authorize confirms the request's principal owns accountID.
+func (s *server) authorize(r *http.Request, accountID int) *apierror.APIError {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		return apierror.ErrUnauthorized
+	}
+	if err := s.authz.CheckOwner(r.Context(), accountID, principal.UserID); err != nil {
+		return apierror.Map(err)
 	}
-	a.Balance -= amount
 	return nil
 }
 
-func (a *Account) GetBalance() float64 {
-	a.mutex.Lock()
-	defer a.mutex.Unlock()
-	return a.Balance
-}
+func (s *server) createAccount(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		apierror.Write(w, apierror.ErrUnauthorized)
+		return
+	}
 
-func createAccount(w http.ResponseWriter, r *http.Request) {
-	mu.Lock()
-	id := idCounter
-	idCounter++
-	mu.Unlock()
+	var req dto.CreateAccountRequest
+	if apiErr := dto.BindAndValidate(r, &req); apiErr != nil {
+		apierror.Write(w, apiErr)
+		return
+	}
 
-	account := &Account{ID: id, Balance: 0}
-	accounts[id] = account
-	log.Printf("Created account with ID: %d at %s", id, time.Now().Format(time.RFC3339))
+	currency := req.Currency
+	if currency == "" {
+		currency = "USD"
+	}
+
+	account, err := s.repo.Create(r.Context(), principal.UserID, currency)
+	if err != nil {
+		apierror.WriteError(w, err)
+		return
+	}
+
+	if req.InitialDeposit > 0 {
+		if err := s.ledger.Deposit(r.Context(), account.ID, req.InitialDeposit); err != nil {
+			apierror.WriteError(w, err)
+			return
+		}
+	}
+
+	log.Printf("created account with ID: %d", account.ID)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]int{"id": id})
+	json.NewEncoder(w).Encode(map[string]any{"id": account.ID, "currency": account.Currency})
 }
 
-func deposit(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, _ := strconv.Atoi(vars["id"])
-
-	var req struct {
-		Amount float64 `json:"amount"`
-	}
-	err := json.NewDecoder(r.Body).Decode(&req)
+func (s *server) deposit(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apierror.Write(w, apierror.ErrAccountNotFound)
 		return
 	}
 
-	log.Printf("Received deposit request: %+v", req)
+	if apiErr := s.authorize(r, id); apiErr != nil {
+		apierror.Write(w, apiErr)
+		return
+	}
 
-	account, exists := accounts[id]
-	if !exists {
-		http.Error(w, "Account not found", http.StatusNotFound)
+	var req dto.DepositRequest
+	if apiErr := dto.BindAndValidate(r, &req); apiErr != nil {
+		apierror.Write(w, apiErr)
 		return
 	}
 
-	go func() {
-		err := account.Deposit(req.Amount)
-		if err != nil {
-			log.Printf("Deposit failed for account ID: %d at %s: %v", id, time.Now().Format(time.RFC3339), err)
-		} else {
-			log.Printf("Deposited %f to account ID: %d at %s", req.Amount, id, time.Now().Format(time.RFC3339))
-		}
-	}()
+	if err := s.ledger.Deposit(r.Context(), id, req.Amount); err != nil {
+		apierror.WriteError(w, err)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
 
-func withdraw(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, _ := strconv.Atoi(vars["id"])
-
-	var req struct {
-		Amount float64 `json:"amount"`
-	}
-	err := json.NewDecoder(r.Body).Decode(&req)
+func (s *server) withdraw(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apierror.Write(w, apierror.ErrAccountNotFound)
 		return
 	}
 
-	log.Printf("Received withdraw request: %+v", req)
+	if apiErr := s.authorize(r, id); apiErr != nil {
+		apierror.Write(w, apiErr)
+		return
+	}
 
-	account, exists := accounts[id]
-	if !exists {
-		http.Error(w, "Account not found", http.StatusNotFound)
+	var req dto.WithdrawRequest
+	if apiErr := dto.BindAndValidate(r, &req); apiErr != nil {
+		apierror.Write(w, apiErr)
 		return
 	}
 
-	go func() {
-		err := account.Withdraw(req.Amount)
-		if err != nil {
-			log.Printf("Withdraw failed for account ID: %d at %s: %v", id, time.Now().Format(time.RFC3339), err)
-		} else {
-			log.Printf("Withdrew %f from account ID: %d at %s", req.Amount, id, time.Now().Format(time.RFC3339))
-		}
-	}()
+	if err := s.ledger.Withdraw(r.Context(), id, req.Amount); err != nil {
+		apierror.WriteError(w, err)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
 
-func getBalance(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, _ := strconv.Atoi(vars["id"])
+func (s *server) getBalance(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Write(w, apierror.ErrAccountNotFound)
+		return
+	}
 
-	account, exists := accounts[id]
-	if !exists {
-		http.Error(w, "Account not found", http.StatusNotFound)
+	if apiErr := s.authorize(r, id); apiErr != nil {
+		apierror.Write(w, apiErr)
 		return
 	}
 
-	balance := account.GetBalance()
-	log.Printf("Checked balance for account ID: %d at %s: %f", id, time.Now().Format(time.RFC3339), balance)
+	balance, err := s.ledger.Balance(r.Context(), id)
+	if err != nil {
+		apierror.WriteError(w, err)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]float64{"balance": balance})
 }
 
+// transfer moves funds between two accounts atomically. Requests carry an
+// idempotency key (header or body), scoped to the caller so two different
+// users can't collide on the same key string; a retried key short-circuits
+// to the response the first attempt produced instead of transferring
+// twice, and a key replayed with different request parameters is rejected
+// as a conflict rather than silently returning the earlier response.
+func (s *server) transfer(w http.ResponseWriter, r *http.Request) {
+	fromID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Write(w, apierror.ErrAccountNotFound)
+		return
+	}
+
+	if apiErr := s.authorize(r, fromID); apiErr != nil {
+		apierror.Write(w, apiErr)
+		return
+	}
+
+	var req dto.TransferRequest
+	if apiErr := dto.BindAndValidate(r, &req); apiErr != nil {
+		apierror.Write(w, apiErr)
+		return
+	}
+
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" {
+		key = req.IdempotencyKey
+	}
+	if key == "" {
+		apierror.Write(w, apierror.ErrInvalidRequest)
+		return
+	}
+
+	principal, _ := auth.FromContext(r.Context())
+	fingerprint := strconv.Itoa(fromID) + ":" + strconv.Itoa(req.ToAccountID) + ":" + strconv.FormatFloat(req.Amount, 'f', -1, 64)
+
+	resp, err := s.idempotency.Execute(principal.UserID, key, fingerprint, func() idempotency.Response {
+		return s.doTransfer(r.Context(), fromID, req.ToAccountID, req.Amount)
+	})
+	if err != nil {
+		apierror.Write(w, apierror.ErrDuplicateIdempotencyKey)
+		return
+	}
+
+	w.Header().Set("Content-Type", resp.ContentType)
+	w.WriteHeader(resp.StatusCode)
+	w.Write(resp.Body)
+}
+
+// transactionItem is the JSON shape of one entry in GET
+// /accounts/{id}/transactions.
+type transactionItem struct {
+	ID                    int       `json:"id"`
+	Type                  string    `json:"type"`
+	Amount                float64   `json:"amount"`
+	CounterpartyAccountID *int      `json:"counterparty_account_id,omitempty"`
+	CreatedAt             time.Time `json:"created_at"`
+	BalanceAfter          float64   `json:"balance_after"`
+}
+
+const defaultTransactionsLimit = 50
+
+// getTransactions returns an account's entries, newest first, cursor-paginated.
+func (s *server) getTransactions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Write(w, apierror.ErrAccountNotFound)
+		return
+	}
+
+	if apiErr := s.authorize(r, id); apiErr != nil {
+		apierror.Write(w, apiErr)
+		return
+	}
+
+	limit := defaultTransactionsLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			apierror.Write(w, apierror.ErrInvalidRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	entries, nextCursor, err := s.ledger.Transactions(r.Context(), id, limit, r.URL.Query().Get("cursor"))
+	if err != nil {
+		apierror.WriteError(w, err)
+		return
+	}
+
+	items := make([]transactionItem, len(entries))
+	for i, e := range entries {
+		items[i] = transactionItem{
+			ID:                    e.ID,
+			Type:                  e.Type,
+			Amount:                e.Amount,
+			CounterpartyAccountID: e.CounterpartyAccountID,
+			CreatedAt:             e.CreatedAt,
+			BalanceAfter:          e.BalanceAfter,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": items, "next_cursor": nextCursor})
+}
+
+func (s *server) doTransfer(ctx context.Context, fromID, toID int, amount float64) idempotency.Response {
+	if err := s.ledger.Transfer(ctx, fromID, toID, amount); err != nil {
+		apiErr := apierror.Map(err)
+		body, _ := json.Marshal(apiErr)
+		return idempotency.Response{StatusCode: apiErr.Status, ContentType: "application/problem+json", Body: body}
+	}
+	body, _ := json.Marshal(map[string]string{"status": "success"})
+	return idempotency.Response{StatusCode: http.StatusOK, ContentType: "application/json", Body: body}
+}
+
+// newVerifier selects the auth.Verifier to use based on AUTH_MODE: "jwks"
+// (the default, suitable for production) validates tokens against
+// JWKS_URL; "dev" trusts the bearer token as-is and must only be used
+// locally.
+func newVerifier() auth.Verifier {
+	if os.Getenv("AUTH_MODE") == "dev" {
+		log.Print("AUTH_MODE=dev: using the static-token verifier, do not run this in production")
+		return auth.StaticTokenVerifier{}
+	}
+	return auth.NewJWKSVerifier(os.Getenv("JWKS_URL"))
+}
+
 func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pool, err := pgxpool.New(ctx, os.Getenv("DATABASE_URL"))
+	if err != nil {
+		log.Fatalf("connect to postgres: %v", err)
+	}
+	defer pool.Close()
+
+	repo := repository.New(pool)
+	s := &server{
+		repo:        repo,
+		authz:       repo,
+		ledger:      ledger.NewPostgresLedger(pool),
+		idempotency: idempotency.New(10_000, 24*time.Hour),
+	}
+
 	router := mux.NewRouter()
-	router.HandleFunc("/accounts", createAccount).Methods("POST")
-	router.HandleFunc("/accounts/{id}/deposit", deposit).Methods("POST")
-	router.HandleFunc("/accounts/{id}/withdraw", withdraw).Methods("POST")
-	router.HandleFunc("/accounts/{id}/balance", getBalance).Methods("GET")
+	router.Use(middleware.Timeout(requestTimeout))
+	router.Use(auth.Middleware(newVerifier()))
+	router.HandleFunc("/accounts", s.createAccount).Methods("POST")
+	router.HandleFunc("/accounts/{id}/deposit", s.deposit).Methods("POST")
+	router.HandleFunc("/accounts/{id}/withdraw", s.withdraw).Methods("POST")
+	router.HandleFunc("/accounts/{id}/transfer", s.transfer).Methods("POST")
+	router.HandleFunc("/accounts/{id}/balance", s.getBalance).Methods("GET")
+	router.HandleFunc("/accounts/{id}/transactions", s.getTransactions).Methods("GET")
+
+	srv := &http.Server{
+		Addr:              ":8080",
+		Handler:           router,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      10 * time.Second,
+		IdleTimeout:       120 * time.Second,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("graceful shutdown failed: %v", err)
+		}
+	}()
+
+	log.Printf("listening on %s", srv.Addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("serve: %v", err)
+	}
 
-	log.Fatal(http.ListenAndServe(":8080", router))
+	// ListenAndServe returns as soon as Shutdown closes the listener, which
+	// is before Shutdown finishes draining in-flight connections. Wait for
+	// the shutdown goroutine to actually finish before the deferred
+	// pool.Close() above runs, so in-flight requests don't see a closed pool.
+	<-done
 }
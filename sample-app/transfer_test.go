@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/Timok27/testtask/sample-app/auth"
+	"github.com/Timok27/testtask/sample-app/idempotency"
+	"github.com/Timok27/testtask/sample-app/ledger"
+)
+
+// allowAllAuthorizer is an accountAuthorizer stub that treats every
+// principal as owning every account.
+type allowAllAuthorizer struct{}
+
+func (allowAllAuthorizer) CheckOwner(ctx context.Context, accountID int, ownerID string) error {
+	return nil
+}
+
+func withTestPrincipal(r *http.Request) *http.Request {
+	return r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{UserID: "test-user"}))
+}
+
+// fakeLedger is an in-memory ledger.Ledger used to exercise handler
+// concurrency without a real database. transferDelay, when set, models the
+// time a real Transfer spends holding row locks against Postgres, so tests
+// can exercise the race window between a request starting and its result
+// being cached.
+type fakeLedger struct {
+	mu            sync.Mutex
+	balances      map[int]float64
+	transferCalls int32
+	transferDelay time.Duration
+}
+
+func (f *fakeLedger) Deposit(ctx context.Context, accountID int, amount float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.balances[accountID] += amount
+	return nil
+}
+
+func (f *fakeLedger) Withdraw(ctx context.Context, accountID int, amount float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.balances[accountID] -= amount
+	return nil
+}
+
+func (f *fakeLedger) Transfer(ctx context.Context, fromID, toID int, amount float64) error {
+	atomic.AddInt32(&f.transferCalls, 1)
+	if f.transferDelay > 0 {
+		time.Sleep(f.transferDelay)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.balances[fromID] -= amount
+	f.balances[toID] += amount
+	return nil
+}
+
+func (f *fakeLedger) Balance(ctx context.Context, accountID int) (float64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.balances[accountID], nil
+}
+
+func (f *fakeLedger) Transactions(ctx context.Context, accountID, limit int, cursor string) ([]ledger.Entry, string, error) {
+	if cursor == "bad-cursor" {
+		return nil, "", ledger.ErrInvalidCursor
+	}
+	return nil, "", nil
+}
+
+// TestTransferIdempotentUnderConcurrency fires the same idempotency key at
+// the transfer handler from many goroutines and asserts the transfer is
+// only ever applied once, even though transferDelay keeps the first
+// request in flight well past when the retries arrive. Run with -race.
+func TestTransferIdempotentUnderConcurrency(t *testing.T) {
+	fl := &fakeLedger{balances: map[int]float64{1: 100, 2: 0}, transferDelay: 5 * time.Millisecond}
+	s := &server{ledger: fl, authz: allowAllAuthorizer{}, idempotency: idempotency.New(100, time.Minute)}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/accounts/{id}/transfer", s.transfer).Methods("POST")
+
+	const concurrency = 20
+	body := []byte(`{"to_account_id":2,"amount":10,"idempotency_key":"same-key"}`)
+
+	var wg sync.WaitGroup
+	codes := make([]int, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := withTestPrincipal(httptest.NewRequest(http.MethodPost, "/accounts/1/transfer", bytes.NewReader(body)))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			codes[i] = rec.Code
+		}(i)
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&fl.transferCalls); got != 1 {
+		t.Fatalf("transfer applied %d times, want 1", got)
+	}
+	for _, code := range codes {
+		if code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", code, http.StatusOK)
+		}
+	}
+}
+
+// TestTransferConflictingIdempotencyKey asserts that replaying a key with
+// different request parameters is rejected rather than silently returning
+// the first response.
+func TestTransferConflictingIdempotencyKey(t *testing.T) {
+	fl := &fakeLedger{balances: map[int]float64{1: 100, 2: 0}}
+	s := &server{ledger: fl, authz: allowAllAuthorizer{}, idempotency: idempotency.New(100, time.Minute)}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/accounts/{id}/transfer", s.transfer).Methods("POST")
+
+	first := withTestPrincipal(httptest.NewRequest(http.MethodPost, "/accounts/1/transfer", bytes.NewReader([]byte(`{"to_account_id":2,"amount":10,"idempotency_key":"shared-key"}`))))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, first)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("first request status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	second := withTestPrincipal(httptest.NewRequest(http.MethodPost, "/accounts/1/transfer", bytes.NewReader([]byte(`{"to_account_id":2,"amount":20,"idempotency_key":"shared-key"}`))))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, second)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("second request status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if got := atomic.LoadInt32(&fl.transferCalls); got != 1 {
+		t.Fatalf("transfer applied %d times, want 1", got)
+	}
+}
+
+// TestTransferScopesIdempotencyKeyByCaller asserts that two different
+// callers reusing the same key string are independent transfers rather
+// than one caller getting back the other's cached response.
+func TestTransferScopesIdempotencyKeyByCaller(t *testing.T) {
+	fl := &fakeLedger{balances: map[int]float64{1: 100, 2: 0}}
+	s := &server{ledger: fl, authz: allowAllAuthorizer{}, idempotency: idempotency.New(100, time.Minute)}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/accounts/{id}/transfer", s.transfer).Methods("POST")
+
+	body := []byte(`{"to_account_id":2,"amount":10,"idempotency_key":"shared-key"}`)
+
+	withPrincipal := func(userID string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/accounts/1/transfer", bytes.NewReader(body))
+		return r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{UserID: userID}))
+	}
+
+	for _, userID := range []string{"user-a", "user-b"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, withPrincipal(userID))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status for %s = %d, want %d", userID, rec.Code, http.StatusOK)
+		}
+	}
+	if got := atomic.LoadInt32(&fl.transferCalls); got != 2 {
+		t.Fatalf("transfer applied %d times, want 2", got)
+	}
+}
+
+// TestTransferAcceptsIdempotencyKeyFromHeader asserts a request that omits
+// idempotency_key from the body, supplying it only via the Idempotency-Key
+// header, is accepted rather than rejected by DTO validation.
+func TestTransferAcceptsIdempotencyKeyFromHeader(t *testing.T) {
+	fl := &fakeLedger{balances: map[int]float64{1: 100, 2: 0}}
+	s := &server{ledger: fl, authz: allowAllAuthorizer{}, idempotency: idempotency.New(100, time.Minute)}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/accounts/{id}/transfer", s.transfer).Methods("POST")
+
+	req := withTestPrincipal(httptest.NewRequest(http.MethodPost, "/accounts/1/transfer", bytes.NewReader([]byte(`{"to_account_id":2,"amount":10}`))))
+	req.Header.Set("Idempotency-Key", "header-key")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := atomic.LoadInt32(&fl.transferCalls); got != 1 {
+		t.Fatalf("transfer applied %d times, want 1", got)
+	}
+}
+
+// TestGetTransactionsRejectsBadInput asserts malformed limit/cursor query
+// parameters are surfaced as 4xx client errors rather than 500s.
+func TestGetTransactionsRejectsBadInput(t *testing.T) {
+	fl := &fakeLedger{balances: map[int]float64{1: 100}}
+	s := &server{ledger: fl, authz: allowAllAuthorizer{}}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/accounts/{id}/transactions", s.getTransactions).Methods("GET")
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{name: "non-numeric limit", query: "limit=abc"},
+		{name: "non-positive limit", query: "limit=0"},
+		{name: "invalid cursor", query: "cursor=bad-cursor"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := withTestPrincipal(httptest.NewRequest(http.MethodGet, "/accounts/1/transactions?"+tc.query, nil))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code < 400 || rec.Code >= 500 {
+				t.Fatalf("status = %d, want 4xx", rec.Code)
+			}
+		})
+	}
+}
+
+func TestTransferRequiresIdempotencyKey(t *testing.T) {
+	fl := &fakeLedger{balances: map[int]float64{1: 100, 2: 0}}
+	s := &server{ledger: fl, authz: allowAllAuthorizer{}, idempotency: idempotency.New(100, time.Minute)}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/accounts/{id}/transfer", s.transfer).Methods("POST")
+
+	req := withTestPrincipal(httptest.NewRequest(http.MethodPost, "/accounts/1/transfer", bytes.NewReader([]byte(`{"to_account_id":2,"amount":10}`))))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}